@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"go/build"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// generatedHeaderRe matches the standard "Code generated ... DO NOT EDIT."
+// marker documented at https://go.dev/s/generatedcode.
+var generatedHeaderRe = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// matchesBuildConfig reports whether path would be compiled under the
+// configured GOOS/GOARCH/tags, using the same logic as the go command's
+// build constraint matching. With -all-tags it always reports true.
+func matchesBuildConfig(path string) (bool, error) {
+	if *allTags {
+		return true, nil
+	}
+	ctx := build.Default
+	ctx.GOOS = *goosFlag
+	ctx.GOARCH = *goarchFlag
+	ctx.BuildTags = splitTags(*buildTags)
+	return ctx.MatchFile(filepath.Dir(path), filepath.Base(path))
+}
+
+// splitTags parses a comma-separated -tags value into individual tags.
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	tags := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			tags = append(tags, p)
+		}
+	}
+	return tags
+}
+
+// isGeneratedFile reports whether path carries a standard
+// "Code generated ... DO NOT EDIT." header in its leading comment block.
+func isGeneratedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if generatedHeaderRe.MatchString(line) {
+			return true, nil
+		}
+		// The header must appear in the leading comment block; stop once we
+		// reach a non-comment, non-blank line.
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "//") {
+			break
+		}
+	}
+	return false, scanner.Err()
+}
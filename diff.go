@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// unifiedDiff renders a unified diff between orig and updated, labeled with
+// path, for use under -dry-run -diff.
+func unifiedDiff(path string, orig, updated []byte) (string, error) {
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(orig)),
+		B:        difflib.SplitLines(string(updated)),
+		FromFile: path,
+		ToFile:   path,
+		Context:  3,
+	}
+	text, err := difflib.GetUnifiedDiffString(diff)
+	if err != nil {
+		return "", fmt.Errorf("compute diff for %s: %w", path, err)
+	}
+	return text, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// rewriteReplaceDirectives rewrites `replace OLD => NEW` directives whose Old
+// path, or whose New path (when it's a module path rather than a local `./`
+// replacement), matches a rule. Because replace entries are keyed by
+// (path, version), a path change is applied as DropReplace + AddReplace
+// rather than mutating the parsed struct in place.
+func rewriteReplaceDirectives(f *modfile.File, rules []*compiledRule, goVer string) (bool, error) {
+	changed := false
+	for _, rep := range append([]*modfile.Replace(nil), f.Replace...) {
+		oldPath, oldVersion := rep.Old.Path, rep.Old.Version
+		newOldPath := oldPath
+		if np, _, ok := matchRules(rules, oldPath, goVer); ok {
+			newOldPath = np
+		}
+
+		newNewPath := rep.New.Path
+		if !isLocalReplacePath(rep.New.Path) {
+			if np, _, ok := matchRules(rules, rep.New.Path, goVer); ok {
+				newNewPath = np
+			}
+		}
+
+		if newOldPath == oldPath && newNewPath == rep.New.Path {
+			continue
+		}
+
+		fmt.Printf("[GO.MOD] replace: %s => %s -> %s => %s\n", oldPath, rep.New.Path, newOldPath, newNewPath)
+		if err := f.DropReplace(oldPath, oldVersion); err != nil {
+			return changed, fmt.Errorf("drop replace %s: %w", oldPath, err)
+		}
+		if err := f.AddReplace(newOldPath, oldVersion, newNewPath, rep.New.Version); err != nil {
+			return changed, fmt.Errorf("add replace %s => %s: %w", newOldPath, newNewPath, err)
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// isLocalReplacePath reports whether a replace directive's RHS is a local
+// filesystem path rather than a module path.
+func isLocalReplacePath(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") || filepath.IsAbs(p)
+}
+
+// rewriteExcludeDirectives rewrites `exclude` directives whose module path
+// matches a rule, via DropExclude + AddExclude.
+func rewriteExcludeDirectives(f *modfile.File, rules []*compiledRule, goVer string) (bool, error) {
+	changed := false
+	for _, ex := range append([]*modfile.Exclude(nil), f.Exclude...) {
+		newPath, _, ok := matchRules(rules, ex.Mod.Path, goVer)
+		if !ok || newPath == ex.Mod.Path {
+			continue
+		}
+		fmt.Printf("[GO.MOD] exclude: %s %s -> %s %s\n", ex.Mod.Path, ex.Mod.Version, newPath, ex.Mod.Version)
+		if err := f.DropExclude(ex.Mod.Path, ex.Mod.Version); err != nil {
+			return changed, fmt.Errorf("drop exclude %s: %w", ex.Mod.Path, err)
+		}
+		if err := f.AddExclude(newPath, ex.Mod.Version); err != nil {
+			return changed, fmt.Errorf("add exclude %s: %w", newPath, err)
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// rewriteRetractRationales updates the free-text rationale comment on
+// `retract` directives when it mentions an old module path (e.g.
+// "see also gopkg.in/yaml.v3 issue #123"), since retract itself targets
+// versions of the current module rather than a dependency path.
+//
+// There's no AddRetract that takes a rationale, and modfile.Format renders
+// comments from the Before/Suffix/After tokens on ret.Syntax, not from the
+// parsed Rationale field -- so the comment token itself has to be rewritten
+// directly, the same reason Replace/Exclude go through DropX/AddX instead of
+// mutating their parsed structs.
+func rewriteRetractRationales(f *modfile.File, rules []*compiledRule, goVer string) bool {
+	changed := false
+	for _, ret := range f.Retract {
+		if ret.Syntax == nil {
+			continue
+		}
+		for i, c := range ret.Syntax.Before {
+			body := strings.TrimPrefix(strings.TrimPrefix(c.Token, "//"), " ")
+			updated, ok := rewriteModulePathMentions(body, rules, goVer)
+			if !ok {
+				continue
+			}
+			newToken := "// " + updated
+			fmt.Printf("[GO.MOD] retract rationale updated: %q -> %q\n", c.Token, newToken)
+			ret.Syntax.Before[i].Token = newToken
+			changed = true
+		}
+	}
+	return changed
+}
+
+// rewriteModulePathMentions rewrites whitespace-delimited words in text that
+// match a rule, preserving trailing punctuation.
+func rewriteModulePathMentions(text string, rules []*compiledRule, goVer string) (string, bool) {
+	if text == "" {
+		return text, false
+	}
+	changed := false
+	fields := strings.Fields(text)
+	for i, word := range fields {
+		trimmed := strings.TrimRight(word, ".,;:)")
+		trailer := word[len(trimmed):]
+		if newPath, _, ok := matchRules(rules, trimmed, goVer); ok && newPath != trimmed {
+			fields[i] = newPath + trailer
+			changed = true
+		}
+	}
+	if !changed {
+		return text, false
+	}
+	return strings.Join(fields, " "), true
+}
+
+// processGoSum drops go.sum `h1:` lines whose module path matches a rule, so
+// a subsequent `go mod tidy` regenerates them against the new path instead of
+// leaving stale checksums that block reproducible builds behind proxies.
+func processGoSum(goModPath string, rules []*compiledRule, goVer string) error {
+	goSumPath := filepath.Join(filepath.Dir(goModPath), "go.sum")
+	data, err := os.ReadFile(goSumPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read go.sum: %w", err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	var kept []string
+	dropped := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			kept = append(kept, line)
+			continue
+		}
+		if _, _, ok := matchRules(rules, fields[0], goVer); ok {
+			dropped++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan go.sum: %w", err)
+	}
+
+	if dropped == 0 {
+		return nil
+	}
+	goModChanged = true
+
+	out := strings.Join(kept, "\n")
+	if len(kept) > 0 {
+		out += "\n"
+	}
+
+	if *dryRun {
+		fmt.Printf("[DRY-RUN] would drop %d stale go.sum entries\n", dropped)
+		if *overlayOut != "" {
+			activeOverlay.Set(goSumPath, []byte(out))
+		}
+		return nil
+	}
+
+	// Stage through the overlay like go.mod and every .go file, so a later
+	// failure in the same run leaves go.sum untouched too.
+	activeOverlay.Set(goSumPath, []byte(out))
+	fmt.Printf("[GO.SUM] staged: drop %d stale entries\n", dropped)
+	return nil
+}
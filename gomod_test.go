@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+func testRules(t *testing.T) []*compiledRule {
+	t.Helper()
+	rules, err := defaultRules()
+	if err != nil {
+		t.Fatalf("defaultRules: %v", err)
+	}
+	return rules
+}
+
+// TestRewriteRetractRationalesUpdatesFormattedOutput guards against
+// rewriteRetractRationales mutating the parsed Rationale field instead of
+// the comment token on ret.Syntax: modfile.Format renders from the syntax
+// tree, so a fix that only touches the struct would leave the old path in
+// the formatted bytes.
+func TestRewriteRetractRationalesUpdatesFormattedOutput(t *testing.T) {
+	src := `module example.com/m
+
+go 1.22
+
+retract (
+	// see also gopkg.in/yaml.v3 issue #123
+	v1.0.0
+)
+`
+	f, err := modfile.Parse("go.mod", []byte(src), nil)
+	if err != nil {
+		t.Fatalf("parse go.mod: %v", err)
+	}
+
+	changed := rewriteRetractRationales(f, testRules(t), "1.22")
+	if !changed {
+		t.Fatal("rewriteRetractRationales reported no change, expected a rewrite")
+	}
+
+	out := modfile.Format(f.Syntax)
+	if strings.Contains(string(out), "gopkg.in/yaml.v3") {
+		t.Errorf("formatted go.mod still contains old path:\n%s", out)
+	}
+	if !strings.Contains(string(out), "go.yaml.in/yaml/v3") {
+		t.Errorf("formatted go.mod missing rewritten path:\n%s", out)
+	}
+}
+
+// TestProcessGoSumStagesThroughOverlay guards against processGoSum writing
+// go.sum straight to disk, which would break the overlay's all-or-nothing
+// commit guarantee.
+func TestProcessGoSumStagesThroughOverlay(t *testing.T) {
+	dir := t.TempDir()
+	goModPath := filepath.Join(dir, "go.mod")
+	goSumPath := filepath.Join(dir, "go.sum")
+
+	original := "gopkg.in/yaml.v3 v3.0.1 h1:deadbeef=\ngopkg.in/yaml.v3 v3.0.1/go.mod h1:deadbeef=\n"
+	if err := os.WriteFile(goSumPath, []byte(original), 0o644); err != nil {
+		t.Fatalf("write go.sum: %v", err)
+	}
+
+	origDryRun, origOverlay := *dryRun, activeOverlay
+	*dryRun = false
+	activeOverlay = newOverlay()
+	defer func() {
+		*dryRun = origDryRun
+		activeOverlay = origOverlay
+	}()
+
+	if err := processGoSum(goModPath, testRules(t), "1.22"); err != nil {
+		t.Fatalf("processGoSum: %v", err)
+	}
+
+	onDisk, err := os.ReadFile(goSumPath)
+	if err != nil {
+		t.Fatalf("read go.sum: %v", err)
+	}
+	if string(onDisk) != original {
+		t.Errorf("go.sum was written directly to disk, bypassing the overlay:\ngot:  %q\nwant: %q", onDisk, original)
+	}
+
+	staged, ok := activeOverlay.entries[goSumPath]
+	if !ok {
+		t.Fatal("processGoSum did not stage go.sum into the overlay")
+	}
+	if strings.Contains(string(staged), "gopkg.in/yaml.v3") {
+		t.Errorf("staged go.sum still contains dropped entries:\n%s", staged)
+	}
+}
@@ -12,7 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -22,35 +22,67 @@ import (
 )
 
 var (
-	scanPath = flag.String("path", ".", "Target project path")
-	dryRun   = flag.Bool("dry-run", false, "Preview changes without writing files")
-
-	// match exact module path like gopkg.in/yaml.v2 (no extra chars)
-	modPathRe = regexp.MustCompile(`^gopkg\.in/yaml\.v([234])$`)
+	scanPath   = flag.String("path", ".", "Target project path")
+	dryRun     = flag.Bool("dry-run", false, "Preview changes without writing files")
+	rulesPath  = flag.String("rules", "", "Path to a YAML/JSON rule table (default: built-in yaml.v2/v3/v4 -> go.yaml.in rules)")
+	vendorMode = flag.Bool("vendor", false, "Also rewrite vendor/modules.txt and move vendored package directories for matched rules")
+
+	buildTags     = flag.String("tags", "", "comma-separated build tags to match when selecting files")
+	goosFlag      = flag.String("goos", runtime.GOOS, "GOOS to match files against")
+	goarchFlag    = flag.String("goarch", runtime.GOARCH, "GOARCH to match files against")
+	allTags       = flag.Bool("all-tags", false, "process every .go file regardless of build constraints")
+	skipGenerated = flag.Bool("skip-generated", false, "skip files with a standard \"Code generated ... DO NOT EDIT.\" header")
+	workers       = flag.Int("j", runtime.NumCPU(), "number of files to process concurrently")
+
+	showDiff   = flag.Bool("diff", false, "with -dry-run, print a unified diff of would-be changes")
+	checkFlag  = flag.Bool("check", false, "like -dry-run, but exit non-zero if any file or go.mod would change (for CI)")
+	overlayOut = flag.String("overlay", "", "write a go build -overlay=-compatible JSON file with the staged changes, without touching the working tree")
 
 	totalFiles   uint64
 	changedFiles uint64
+	goModChanged bool
+
+	activeRules   []*compiledRule
+	activeGoVer   string
+	activeOverlay = newOverlay()
 )
 
 func main() {
 	flag.Parse()
 
+	if *checkFlag {
+		*dryRun = true
+	}
+
 	start := time.Now()
 	fmt.Println("== yaml-ast-migrator started ==")
 	fmt.Printf("path: %s\n", *scanPath)
 	fmt.Printf("dry-run: %v\n\n", *dryRun)
 
-	goVer := readGoVersion(*scanPath)
-	fmt.Printf("detected go directive: %s\n", goVer)
-	if compareGo(goVer, "1.22") < 0 {
-		fmt.Printf("ERROR: Detected Go %s. go.yaml.in/yaml requires Go 1.22+\n", goVer)
+	var err error
+	if *rulesPath != "" {
+		activeRules, err = loadRules(*rulesPath)
+	} else {
+		activeRules, err = defaultRules()
+	}
+	if err != nil {
+		fmt.Printf("ERROR loading rules: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("loaded %d rule(s)\n", len(activeRules))
+
+	activeGoVer = readGoVersion(*scanPath)
+	fmt.Printf("detected go directive: %s\n", activeGoVer)
 
-	if err := filepath.WalkDir(*scanPath, visit); err != nil {
+	paths, err := collectFiles(*scanPath)
+	if err != nil {
 		fmt.Printf("ERROR: walk failed: %v\n", err)
 		os.Exit(1)
 	}
+	if err := processFilesParallel(paths); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
 
 	// update go.mod after walking files (we may have the version info from go.mod already)
 	goModPath := filepath.Join(*scanPath, "go.mod")
@@ -61,18 +93,61 @@ func main() {
 		}
 	}
 
-	if !*dryRun {
+	// processVendorTree's renameVendorDir walks the moved subtree through the
+	// same processGoFile path, staging more entries into activeOverlay; run
+	// it before the overlay is written/committed so nothing it stages is
+	// left behind.
+	if *vendorMode {
+		fmt.Println("\n== rewriting vendor tree ==")
+		if err := processVendorTree(*scanPath, activeRules); err != nil {
+			fmt.Printf("ERROR updating vendor tree: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Every file, go.mod, and any vendor-subtree rewrite parsed, rewrote, and
+	// validated successfully, so it's now safe to flush (or preview) the
+	// staged overlay. With -overlay, the working tree is left untouched even
+	// outside -dry-run, so the JSON can be handed to `go build -overlay=`
+	// for a full preview.
+	if *overlayOut != "" {
+		if err := activeOverlay.WriteGoOverlay(*overlayOut); err != nil {
+			fmt.Printf("ERROR writing overlay: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nwrote overlay: %s\n", *overlayOut)
+	} else if !*dryRun {
+		if err := activeOverlay.Commit(); err != nil {
+			fmt.Printf("ERROR committing changes: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch {
+	case *dryRun || *overlayOut != "":
+		fmt.Println("\n[DRY-RUN] skipping go mod tidy/vendor")
+	case *vendorMode:
+		fmt.Println("\n== running: go mod vendor ==")
+		if err := runGoModVendor(*scanPath); err != nil {
+			fmt.Printf("go mod vendor failed: %v\n", err)
+		} else {
+			fmt.Println("go mod vendor finished")
+		}
+	default:
 		fmt.Println("\n== running: go mod tidy ==")
 		if err := runGoModTidy(*scanPath); err != nil {
 			fmt.Printf("go mod tidy failed: %v\n", err)
 		} else {
 			fmt.Println("go mod tidy finished")
 		}
-	} else {
-		fmt.Println("\n[DRY-RUN] skipping go mod tidy")
 	}
 
 	fmt.Printf("\nscanned files: %d\nchanged files: %d\ncompleted in %s\n", totalFiles, changedFiles, time.Since(start))
+
+	if *checkFlag && (changedFiles > 0 || goModChanged) {
+		fmt.Println("\n[CHECK] migration would change files")
+		os.Exit(1)
+	}
 }
 
 // visit handles files during filepath.WalkDir
@@ -93,27 +168,58 @@ func visit(path string, d fs.DirEntry, err error) error {
 		return nil
 	}
 
+	matched, err := matchesBuildConfig(path)
+	if err != nil {
+		return fmt.Errorf("check build constraints %s: %w", path, err)
+	}
+	if !matched {
+		fmt.Printf("[SKIP] %s (build constraints)\n", path)
+		return nil
+	}
+
+	if *skipGenerated {
+		generated, err := isGeneratedFile(path)
+		if err != nil {
+			return fmt.Errorf("check generated header %s: %w", path, err)
+		}
+		if generated {
+			fmt.Printf("[SKIP] %s (generated)\n", path)
+			return nil
+		}
+	}
+
 	atomic.AddUint64(&totalFiles, 1)
 	fmt.Printf("[SCAN] %s\n", path)
 
-	changed, err := processGoFile(path)
+	changed, diffText, err := processGoFile(path)
 	if err != nil {
 		return fmt.Errorf("process %s: %w", path, err)
 	}
 	if changed {
 		atomic.AddUint64(&changedFiles, 1)
-		fmt.Printf("[UPDATED] %s\n", path)
+	}
+	for _, line := range changeLogLines(path, changed) {
+		fmt.Println(line)
+	}
+	if diffText != "" {
+		fmt.Print(diffText)
 	}
 	return nil
 }
 
-// processGoFile parses a .go file, updates import specs if needed, and writes the file back.
-// Returns (changed, error).
-func processGoFile(path string) (bool, error) {
+// processGoFile parses a .go file, updates import specs if needed, and writes
+// the file back. Returns (changed, unifiedDiff, error); unifiedDiff is only
+// populated under -dry-run -diff.
+func processGoFile(path string) (bool, string, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, "", fmt.Errorf("read file: %w", err)
+	}
+
 	fset := token.NewFileSet()
-	parsedFile, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	parsedFile, err := parser.ParseFile(fset, path, src, parser.ParseComments)
 	if err != nil {
-		return false, fmt.Errorf("parse file: %w", err)
+		return false, "", fmt.Errorf("parse file: %w", err)
 	}
 
 	changed := false
@@ -126,9 +232,7 @@ func processGoFile(path string) (bool, error) {
 			// skip malformed
 			continue
 		}
-		if m := modPathRe.FindStringSubmatch(raw); m != nil {
-			major := m[1]
-			newPath := "go.yaml.in/yaml/v" + major
+		if newPath, _, ok := matchRules(activeRules, raw, activeGoVer); ok {
 			if raw != newPath {
 				imp.Path.Value = strconvQuote(newPath) // set quoted value
 				changed = true
@@ -137,7 +241,7 @@ func processGoFile(path string) (bool, error) {
 	}
 
 	if !changed {
-		return false, nil
+		return false, "", nil
 	}
 
 	// write AST back to source (preserve format reasonably)
@@ -145,23 +249,34 @@ func processGoFile(path string) (bool, error) {
 	// Use printer config to print file; then run go/format to ensure canonical formatting
 	cfg := &printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
 	if err := cfg.Fprint(&buf, fset, parsedFile); err != nil {
-		return false, fmt.Errorf("printing AST: %w", err)
+		return false, "", fmt.Errorf("printing AST: %w", err)
 	}
 	formatted, err := format.Source(buf.Bytes())
 	if err != nil {
-		// fallback: use unformatted AST output
-		formatted = buf.Bytes()
+		return false, "", fmt.Errorf("format rewritten source: %w", err)
 	}
 
-	if *dryRun {
-		fmt.Printf("[DRY-RUN] would update %s\n", path)
-		return true, nil
+	// Prove the rewritten bytes still compile syntactically before staging
+	// them, so a bad rewrite never makes it into the overlay.
+	if _, err := parser.ParseFile(token.NewFileSet(), path, formatted, parser.ParseComments); err != nil {
+		return false, "", fmt.Errorf("validate rewritten source: %w", err)
+	}
+
+	var diffText string
+	if *dryRun && *showDiff {
+		diffText, err = unifiedDiff(path, src, formatted)
+		if err != nil {
+			return false, "", err
+		}
 	}
 
-	if err := os.WriteFile(path, formatted, 0o644); err != nil {
-		return false, fmt.Errorf("write file: %w", err)
+	// Stage into the overlay whenever it'll actually be consumed: a real
+	// (non-dry-run) write, or a -overlay preview, which needs the staged
+	// content even though -dry-run is set.
+	if !*dryRun || *overlayOut != "" {
+		activeOverlay.Set(path, formatted)
 	}
-	return true, nil
+	return true, diffText, nil
 }
 
 // processGoMod uses golang.org/x/mod/modfile to update require entries
@@ -176,34 +291,78 @@ func processGoMod(modPath string) error {
 	}
 
 	changed := false
-	// iterate require entries and update module path if matches
-	for _, r := range f.Require {
-		if m := modPathRe.FindStringSubmatch(r.Mod.Path); m != nil {
-			major := m[1]
-			newPath := "go.yaml.in/yaml/v" + major
-			if r.Mod.Path != newPath {
-				fmt.Printf("[GO.MOD] require: %s %s -> %s %s\n", r.Mod.Path, r.Mod.Version, newPath, r.Mod.Version)
-				r.Mod.Path = newPath
-				changed = true
-			}
+	// iterate require entries and update module path if matches. Require
+	// entries are keyed by path, so a path change is applied as
+	// DropRequire + AddRequire rather than mutating req.Mod in place --
+	// modfile.Format renders from f.Syntax, not the parsed Require structs,
+	// so an in-place mutation would silently never reach the output.
+	for _, req := range append([]*modfile.Require(nil), f.Require...) {
+		newPath, rule, ok := matchRules(activeRules, req.Mod.Path, activeGoVer)
+		if !ok || req.Mod.Path == newPath {
+			continue
+		}
+		newVersion := req.Mod.Version
+		if rule.VersionPin != "" {
+			newVersion = rule.VersionPin
 		}
+		fmt.Printf("[GO.MOD] require: %s %s -> %s %s\n", req.Mod.Path, req.Mod.Version, newPath, newVersion)
+		if err := f.DropRequire(req.Mod.Path); err != nil {
+			return fmt.Errorf("drop require %s: %w", req.Mod.Path, err)
+		}
+		if err := f.AddRequire(newPath, newVersion); err != nil {
+			return fmt.Errorf("add require %s %s: %w", newPath, newVersion, err)
+		}
+		changed = true
+	}
+
+	replaceChanged, err := rewriteReplaceDirectives(f, activeRules, activeGoVer)
+	if err != nil {
+		return fmt.Errorf("rewrite replace directives: %w", err)
+	}
+	changed = changed || replaceChanged
+
+	excludeChanged, err := rewriteExcludeDirectives(f, activeRules, activeGoVer)
+	if err != nil {
+		return fmt.Errorf("rewrite exclude directives: %w", err)
+	}
+	changed = changed || excludeChanged
+
+	changed = rewriteRetractRationales(f, activeRules, activeGoVer) || changed
+
+	if err := processGoSum(modPath, activeRules, activeGoVer); err != nil {
+		return fmt.Errorf("process go.sum: %w", err)
 	}
 
 	if !changed {
 		return nil
 	}
+	goModChanged = true
+
+	// Correcting the modfile.Format usage to handle its single return value
+	newBytes := modfile.Format(f.Syntax)
+
+	// Prove the rewritten go.mod still parses before staging it.
+	if _, err := modfile.Parse(modPath, newBytes, nil); err != nil {
+		return fmt.Errorf("validate rewritten go.mod: %w", err)
+	}
 
 	if *dryRun {
 		fmt.Println("[DRY-RUN] would write go.mod changes")
+		if *showDiff {
+			diffText, err := unifiedDiff(modPath, data, newBytes)
+			if err != nil {
+				return err
+			}
+			fmt.Print(diffText)
+		}
+		if *overlayOut != "" {
+			activeOverlay.Set(modPath, newBytes)
+		}
 		return nil
 	}
 
-	// Correcting the modfile.Format usage to handle its single return value
-	newBytes := modfile.Format(f.Syntax)
-	if err := os.WriteFile(modPath, newBytes, 0o644); err != nil {
-		return fmt.Errorf("write go.mod: %w", err)
-	}
-	fmt.Println("[GO.MOD] updated")
+	activeOverlay.Set(modPath, newBytes)
+	fmt.Println("[GO.MOD] staged")
 	return nil
 }
 
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestProcessGoModRewritesRequireEntries guards against processGoMod
+// mutating the parsed *modfile.Require structs (req.Mod.Path/Version)
+// instead of going through AddRequire/DropRequire: modfile.Format renders
+// from the syntax tree, so a struct-only mutation is silently discarded and
+// the staged bytes come out identical to the input.
+func TestProcessGoModRewritesRequireEntries(t *testing.T) {
+	dir := t.TempDir()
+	goModPath := filepath.Join(dir, "go.mod")
+	original := `module example.com/m
+
+go 1.22
+
+require gopkg.in/yaml.v3 v3.0.1
+`
+	if err := os.WriteFile(goModPath, []byte(original), 0o644); err != nil {
+		t.Fatalf("write go.mod: %v", err)
+	}
+
+	origRules, origGoVer, origDryRun, origOverlay := activeRules, activeGoVer, *dryRun, activeOverlay
+	rules := testRules(t)
+	activeRules = rules
+	activeGoVer = "1.22"
+	*dryRun = false
+	activeOverlay = newOverlay()
+	defer func() {
+		activeRules, activeGoVer, *dryRun, activeOverlay = origRules, origGoVer, origDryRun, origOverlay
+	}()
+
+	if err := processGoMod(goModPath); err != nil {
+		t.Fatalf("processGoMod: %v", err)
+	}
+
+	staged, ok := activeOverlay.entries[goModPath]
+	if !ok {
+		t.Fatal("processGoMod did not stage a rewritten go.mod")
+	}
+	if string(staged) == original {
+		t.Fatal("staged go.mod is byte-for-byte identical to the input; the require rewrite never reached the output")
+	}
+	if strings.Contains(string(staged), "gopkg.in/yaml.v3") {
+		t.Errorf("staged go.mod still references the old path:\n%s", staged)
+	}
+	if !strings.Contains(string(staged), "go.yaml.in/yaml/v3 v3.0.1") {
+		t.Errorf("staged go.mod missing rewritten require:\n%s", staged)
+	}
+}
+
+// TestOverlayPreviewDoesNotMutateWorkingTree guards against the two
+// -overlay bugs: Set being skipped entirely under -dry-run (leaving the
+// overlay empty), and the real Commit() running anyway once -dry-run is
+// dropped (mutating the tree despite -overlay being requested).
+func TestOverlayPreviewDoesNotMutateWorkingTree(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	original := "package p\n\nimport \"gopkg.in/yaml.v3\"\n\nvar _ = yaml.Marshal\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	origRules, origGoVer, origDryRun, origOverlayOut, origOverlay := activeRules, activeGoVer, *dryRun, *overlayOut, activeOverlay
+	activeRules = testRules(t)
+	activeGoVer = "1.22"
+	*dryRun = true
+	*overlayOut = filepath.Join(dir, "overlay.json")
+	activeOverlay = newOverlay()
+	defer func() {
+		activeRules, activeGoVer, *dryRun, *overlayOut, activeOverlay = origRules, origGoVer, origDryRun, origOverlayOut, origOverlay
+	}()
+
+	changed, _, err := processGoFile(path)
+	if err != nil {
+		t.Fatalf("processGoFile: %v", err)
+	}
+	if !changed {
+		t.Fatal("expected processGoFile to report a change")
+	}
+
+	staged, ok := activeOverlay.entries[path]
+	if !ok {
+		t.Fatal("-dry-run -overlay produced no staged content; the overlay preview is unreachable")
+	}
+	if strings.Contains(string(staged), "gopkg.in/yaml.v3") {
+		t.Errorf("staged content still references the old path:\n%s", staged)
+	}
+
+	onDisk, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read source: %v", err)
+	}
+	if string(onDisk) != original {
+		t.Error("-overlay mode mutated the working tree; it must only write the overlay JSON")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Overlay stages rewritten file contents in memory so a run validates every
+// file before anything touches disk: Set populates it during the
+// parse/rewrite/validate phase, Commit flushes it only after every file in
+// the run has succeeded. If any file fails validation first, Commit is never
+// called and the working tree is untouched.
+type Overlay struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func newOverlay() *Overlay {
+	return &Overlay{entries: make(map[string][]byte)}
+}
+
+// Set stages new content for path, to be written by Commit.
+func (o *Overlay) Set(path string, content []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries[path] = content
+}
+
+// Commit flushes every staged file to disk using a temp-file + os.Rename per
+// file, in path-sorted order.
+func (o *Overlay) Commit() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	paths := o.sortedPathsLocked()
+	for _, p := range paths {
+		if err := atomicWriteFile(p, o.entries[p], 0o644); err != nil {
+			return fmt.Errorf("commit %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// WriteGoOverlay writes the staged content as a go build -overlay=-compatible
+// JSON file at overlayPath, with replacement content stored in a sibling
+// "<overlayPath>.content" directory, so users can preview a full build
+// without ever mutating their working tree.
+func (o *Overlay) WriteGoOverlay(overlayPath string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	contentDir := overlayPath + ".content"
+	if err := os.MkdirAll(contentDir, 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", contentDir, err)
+	}
+
+	paths := o.sortedPathsLocked()
+	replace := make(map[string]string, len(paths))
+	for i, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", p, err)
+		}
+		shadowPath := filepath.Join(contentDir, fmt.Sprintf("%04d_%s", i, filepath.Base(p)))
+		if err := os.WriteFile(shadowPath, o.entries[p], 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", shadowPath, err)
+		}
+		shadowAbs, err := filepath.Abs(shadowPath)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", shadowPath, err)
+		}
+		replace[abs] = shadowAbs
+	}
+
+	doc := struct {
+		Replace map[string]string `json:"Replace"`
+	}{Replace: replace}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal overlay: %w", err)
+	}
+	return os.WriteFile(overlayPath, data, 0o644)
+}
+
+func (o *Overlay) sortedPathsLocked() []string {
+	paths := make([]string, 0, len(o.entries))
+	for p := range o.entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// atomicWriteFile writes data to path via a temp file in the same directory
+// followed by os.Rename, so a crash mid-write never leaves a truncated file.
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".migrator-tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule describes a single module path rewrite: any import or go.mod
+// require/replace entry matching From is rewritten to To. From is compiled
+// as a regexp (exact paths work unchanged since they have no metacharacters
+// once anchored), and To is expanded against the match using the same
+// capture-group syntax as regexp.Expand ($1, $2, ...).
+type Rule struct {
+	Name string `yaml:"name" json:"name"`
+	From string `yaml:"from" json:"from"`
+	To   string `yaml:"to" json:"to"`
+
+	// MinGo, if set, is the minimum `go` directive version required for this
+	// rule to apply (e.g. "1.22" for go.yaml.in/yaml, which needs generics).
+	MinGo string `yaml:"min_go,omitempty" json:"min_go,omitempty"`
+
+	// VersionPin, if set, is the go.mod version string to pin the new
+	// module to (e.g. "v3.0.1"). Empty means keep the existing version.
+	VersionPin string `yaml:"version_pin,omitempty" json:"version_pin,omitempty"`
+}
+
+// compiledRule is a Rule with its From pattern compiled and anchored.
+type compiledRule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+//go:embed rules/default.yaml
+var defaultRulesYAML []byte
+
+// defaultRules returns the built-in rule set, which reproduces the tool's
+// original single-purpose behavior: gopkg.in/yaml.v2, v3, and v4 all move to
+// go.yaml.in/yaml/vN.
+func defaultRules() ([]*compiledRule, error) {
+	rules, err := parseRules(defaultRulesYAML, "yaml")
+	if err != nil {
+		return nil, fmt.Errorf("parse embedded default rules: %w", err)
+	}
+	return rules, nil
+}
+
+// loadRules reads a rule table from a YAML or JSON file, chosen by the
+// path's extension (.json vs anything else treated as YAML).
+func loadRules(path string) ([]*compiledRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules file: %w", err)
+	}
+	format := "yaml"
+	if strings.HasSuffix(path, ".json") {
+		format = "json"
+	}
+	rules, err := parseRules(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("parse rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func parseRules(data []byte, format string) ([]*compiledRule, error) {
+	var raw struct {
+		Rules []Rule `yaml:"rules" json:"rules"`
+	}
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := make([]*compiledRule, 0, len(raw.Rules))
+	for _, r := range raw.Rules {
+		re, err := compileRulePattern(r.From)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		compiled = append(compiled, &compiledRule{Rule: r, re: re})
+	}
+	return compiled, nil
+}
+
+// compileRulePattern compiles a rule's From field as an anchored regexp. A
+// From value with no regexp metacharacters behaves as an exact-match rule.
+func compileRulePattern(from string) (*regexp.Regexp, error) {
+	pattern := from
+	if !strings.HasPrefix(pattern, "^") {
+		pattern = "^" + pattern
+	}
+	if !strings.HasSuffix(pattern, "$") {
+		pattern = pattern + "$"
+	}
+	return regexp.Compile(pattern)
+}
+
+// apply matches modPath against the rule and, if it matches and the rule's
+// MinGo requirement (if any) is satisfied by goVer, returns the rewritten
+// path and true. goVer may be empty to skip the MinGo check (e.g. when the
+// caller has already verified it once for the whole run).
+func (r *compiledRule) apply(modPath, goVer string) (string, bool) {
+	loc := r.re.FindStringSubmatchIndex(modPath)
+	if loc == nil {
+		return "", false
+	}
+	if r.MinGo != "" && goVer != "" && compareGo(goVer, r.MinGo) < 0 {
+		return "", false
+	}
+	newPath := string(r.re.ExpandString(nil, r.To, modPath, loc))
+	return newPath, true
+}
+
+// matchRules runs modPath through rules in order and returns the first
+// match's rewritten path.
+func matchRules(rules []*compiledRule, modPath, goVer string) (string, *compiledRule, bool) {
+	for _, r := range rules {
+		if newPath, ok := r.apply(modPath, goVer); ok {
+			return newPath, r, true
+		}
+	}
+	return "", nil, false
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+const sampleRulesYAML = `
+rules:
+  - name: yaml-family
+    from: gopkg\.in/(.+)\.v([0-9]+)
+    to: go.yaml.in/$1/v$2
+    min_go: "1.22"
+  - name: pkg-errors-to-stdlib
+    from: github.com/pkg/errors
+    to: errors
+`
+
+const sampleRulesJSON = `
+{
+  "rules": [
+    {"name": "yaml-family", "from": "gopkg\\.in/(.+)\\.v([0-9]+)", "to": "go.yaml.in/$1/v$2", "min_go": "1.22"},
+    {"name": "pkg-errors-to-stdlib", "from": "github.com/pkg/errors", "to": "errors"}
+  ]
+}
+`
+
+func TestParseRulesYAML(t *testing.T) {
+	rules, err := parseRules([]byte(sampleRulesYAML), "yaml")
+	if err != nil {
+		t.Fatalf("parseRules(yaml): %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2", len(rules))
+	}
+	if rules[0].Name != "yaml-family" || rules[1].Name != "pkg-errors-to-stdlib" {
+		t.Errorf("unexpected rule names: %q, %q", rules[0].Name, rules[1].Name)
+	}
+}
+
+func TestParseRulesJSON(t *testing.T) {
+	rules, err := parseRules([]byte(sampleRulesJSON), "json")
+	if err != nil {
+		t.Fatalf("parseRules(json): %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2", len(rules))
+	}
+	if rules[0].MinGo != "1.22" {
+		t.Errorf("MinGo = %q, want 1.22", rules[0].MinGo)
+	}
+}
+
+func TestLoadRulesPicksFormatByExtension(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := dir + "/rules.json"
+	if err := os.WriteFile(jsonPath, []byte(sampleRulesJSON), 0o644); err != nil {
+		t.Fatalf("write rules.json: %v", err)
+	}
+	rules, err := loadRules(jsonPath)
+	if err != nil {
+		t.Fatalf("loadRules(.json): %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2", len(rules))
+	}
+
+	yamlPath := dir + "/rules.yaml"
+	if err := os.WriteFile(yamlPath, []byte(sampleRulesYAML), 0o644); err != nil {
+		t.Fatalf("write rules.yaml: %v", err)
+	}
+	rules, err = loadRules(yamlPath)
+	if err != nil {
+		t.Fatalf("loadRules(.yaml): %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2", len(rules))
+	}
+}
+
+// TestApplyCaptureGroupExpansion covers the backlog's own motivating example
+// for rule tables: a regex From with capture groups expanded into To via
+// $1/$2, alongside a plain exact-match rename with no captures at all
+// (github.com/pkg/errors -> errors).
+func TestApplyCaptureGroupExpansion(t *testing.T) {
+	rules, err := parseRules([]byte(sampleRulesYAML), "yaml")
+	if err != nil {
+		t.Fatalf("parseRules: %v", err)
+	}
+
+	newPath, rule, ok := matchRules(rules, "gopkg.in/yaml.v3", "1.22")
+	if !ok {
+		t.Fatal("expected gopkg.in/yaml.v3 to match the yaml-family rule")
+	}
+	if newPath != "go.yaml.in/yaml/v3" {
+		t.Errorf("newPath = %q, want go.yaml.in/yaml/v3", newPath)
+	}
+	if rule.Name != "yaml-family" {
+		t.Errorf("matched rule = %q, want yaml-family", rule.Name)
+	}
+
+	newPath, _, ok = matchRules(rules, "github.com/pkg/errors", "1.18")
+	if !ok {
+		t.Fatal("expected github.com/pkg/errors to match the pkg-errors-to-stdlib rule")
+	}
+	if newPath != "errors" {
+		t.Errorf("newPath = %q, want errors", newPath)
+	}
+}
+
+// TestApplyMinGoGating guards the per-rule MinGo check used to replace the
+// old blanket "requires Go 1.22+" gate: a rule with MinGo should not match
+// below that version, but an unrelated rule with no MinGo at all (like the
+// pkg/errors rename) must still apply regardless of the project's Go
+// version.
+func TestApplyMinGoGating(t *testing.T) {
+	rules, err := parseRules([]byte(sampleRulesYAML), "yaml")
+	if err != nil {
+		t.Fatalf("parseRules: %v", err)
+	}
+
+	if _, _, ok := matchRules(rules, "gopkg.in/yaml.v3", "1.18"); ok {
+		t.Error("expected yaml-family rule (min_go 1.22) not to match under go 1.18")
+	}
+	if _, _, ok := matchRules(rules, "gopkg.in/yaml.v3", "1.22"); !ok {
+		t.Error("expected yaml-family rule to match under go 1.22")
+	}
+	if _, _, ok := matchRules(rules, "github.com/pkg/errors", "1.18"); !ok {
+		t.Error("expected pkg-errors rule (no min_go) to match regardless of go version")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// vendorRewrite records a single module rename applied to vendor/modules.txt,
+// so the corresponding on-disk vendor directory can be moved afterward.
+type vendorRewrite struct {
+	oldPath string
+	newPath string
+}
+
+// processVendorTree rewrites vendor/modules.txt for any module matched by
+// rules and moves the corresponding vendored source directories to match.
+// It is a no-op (not an error) when the project has no vendor directory.
+func processVendorTree(root string, rules []*compiledRule) error {
+	modulesTxtPath := filepath.Join(root, "vendor", "modules.txt")
+	data, err := os.ReadFile(modulesTxtPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read vendor/modules.txt: %w", err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	var rewrites []vendorRewrite
+	changed := false
+	curOld, curNew := "", ""
+	curMatched := false
+
+	for i, ln := range lines {
+		switch {
+		case strings.HasPrefix(ln, "# "):
+			fields := strings.Fields(strings.TrimPrefix(ln, "# "))
+			if len(fields) == 0 {
+				continue
+			}
+			oldMod := fields[0]
+			curOld, curMatched = oldMod, false
+			if newMod, _, ok := matchRules(rules, oldMod, ""); ok && newMod != oldMod {
+				fields[0] = newMod
+				lines[i] = "# " + strings.Join(fields, " ")
+				rewrites = append(rewrites, vendorRewrite{oldPath: oldMod, newPath: newMod})
+				changed = true
+				curNew, curMatched = newMod, true
+			}
+		case strings.HasPrefix(ln, "##"):
+			// annotation line (e.g. "## explicit; go 1.20") - module path stays as-is
+		case strings.TrimSpace(ln) == "":
+			// blank
+		default:
+			// package import path belonging to the current module block
+			if curMatched && (ln == curOld || strings.HasPrefix(ln, curOld+"/")) {
+				lines[i] = curNew + ln[len(curOld):]
+			}
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	// -dry-run and -overlay both preview only: gated the same way
+	// processGoFile/processGoSum are, neither writes modules.txt nor moves a
+	// single vendor directory.
+	if *dryRun || *overlayOut != "" {
+		fmt.Println("[DRY-RUN] would update vendor/modules.txt")
+		for _, rw := range rewrites {
+			fmt.Printf("[DRY-RUN] would move vendor/%s -> vendor/%s\n", rw.oldPath, rw.newPath)
+		}
+		return nil
+	}
+
+	// Move every vendored directory before touching modules.txt, rolling back
+	// anything already moved if one fails partway. Otherwise a mid-loop
+	// rename failure would leave modules.txt referencing paths that were
+	// never actually moved: an inconsistent vendor tree with no way back.
+	var moved []vendorRewrite
+	for _, rw := range rewrites {
+		if err := renameVendorDir(root, rw.oldPath, rw.newPath); err != nil {
+			for _, done := range moved {
+				renameVendorDirBack(root, done.oldPath, done.newPath)
+			}
+			return fmt.Errorf("rename vendored %s: %w", rw.oldPath, err)
+		}
+		moved = append(moved, rw)
+	}
+
+	// Stage modules.txt alongside go.mod and every .go file, so it's flushed
+	// by the same final activeOverlay.Commit() in main() instead of being
+	// written to disk ahead of everything else in the run.
+	activeOverlay.Set(modulesTxtPath, []byte(strings.Join(lines, "\n")))
+	fmt.Println("[VENDOR] staged vendor/modules.txt")
+	return nil
+}
+
+// renameVendorDir moves the on-disk vendor directory for a renamed module,
+// creating intermediate directories and pruning empty parents left behind,
+// then walks the moved subtree to fix up its own internal imports.
+func renameVendorDir(root, oldMod, newMod string) error {
+	vendorDir := filepath.Join(root, "vendor")
+	oldDir := filepath.Join(vendorDir, filepath.FromSlash(oldMod))
+	newDir := filepath.Join(vendorDir, filepath.FromSlash(newMod))
+
+	if !fileExists(oldDir) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newDir), 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(newDir), err)
+	}
+	if err := os.Rename(oldDir, newDir); err != nil {
+		return fmt.Errorf("rename %s -> %s: %w", oldDir, newDir, err)
+	}
+	fmt.Printf("[VENDOR] moved %s -> %s\n", oldDir, newDir)
+
+	removeEmptyParents(vendorDir, filepath.Dir(oldDir))
+
+	// Rewrite internal imports within the moved subtree too (e.g. a
+	// multi-package module that imports its own subpackages by full path).
+	if err := filepath.WalkDir(newDir, visit); err != nil {
+		return fmt.Errorf("rewrite imports under %s: %w", newDir, err)
+	}
+	return nil
+}
+
+// renameVendorDirBack reverses a renameVendorDir move during rollback, after
+// a later rewrite in the same batch has failed. It's best-effort: the caller
+// is already returning the original error, so a rollback failure is logged
+// rather than compounding it.
+func renameVendorDirBack(root, oldMod, newMod string) {
+	vendorDir := filepath.Join(root, "vendor")
+	oldDir := filepath.Join(vendorDir, filepath.FromSlash(oldMod))
+	newDir := filepath.Join(vendorDir, filepath.FromSlash(newMod))
+
+	if err := os.MkdirAll(filepath.Dir(oldDir), 0o755); err != nil {
+		fmt.Printf("WARNING: rollback mkdir %s failed, %s left at %s: %v\n", filepath.Dir(oldDir), oldMod, newDir, err)
+		return
+	}
+	if err := os.Rename(newDir, oldDir); err != nil {
+		fmt.Printf("WARNING: rollback failed, %s left at %s: %v\n", oldMod, newDir, err)
+		return
+	}
+	fmt.Printf("[VENDOR] rolled back %s -> %s\n", newDir, oldDir)
+}
+
+// removeEmptyParents removes dir and any now-empty ancestor directories, up
+// to but not including root, left behind after a rename.
+func removeEmptyParents(root, dir string) {
+	for dir != root && dir != "." && dir != string(filepath.Separator) {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// runGoModVendor runs `go mod vendor` in the given directory, used instead of
+// `go mod tidy` when -vendor is set so the vendor tree stays authoritative.
+func runGoModVendor(dir string) error {
+	cmd := exec.Command("go", "mod", "vendor")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
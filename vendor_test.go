@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleModulesTxt = `# gopkg.in/yaml.v3 v3.0.1
+## explicit; go 1.15
+gopkg.in/yaml.v3
+`
+
+func setupVendorTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	vendorDir := filepath.Join(root, "vendor", "gopkg.in", "yaml.v3")
+	if err := os.MkdirAll(vendorDir, 0o755); err != nil {
+		t.Fatalf("mkdir vendor dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vendorDir, "yaml.go"), []byte("package yaml\n"), 0o644); err != nil {
+		t.Fatalf("write vendored file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "vendor", "modules.txt"), []byte(sampleModulesTxt), 0o644); err != nil {
+		t.Fatalf("write modules.txt: %v", err)
+	}
+	return root
+}
+
+// TestProcessVendorTreeOverlayPreviewLeavesTreeUntouched guards against
+// processVendorTree/renameVendorDir writing modules.txt and moving vendor
+// directories unconditionally whenever !*dryRun: with -overlay requested
+// (even without -dry-run), nothing on disk should move.
+func TestProcessVendorTreeOverlayPreviewLeavesTreeUntouched(t *testing.T) {
+	root := setupVendorTree(t)
+
+	origRules, origGoVer, origDryRun, origOverlayOut, origOverlay := activeRules, activeGoVer, *dryRun, *overlayOut, activeOverlay
+	activeRules = testRules(t)
+	activeGoVer = "1.22"
+	*dryRun = false
+	*overlayOut = filepath.Join(root, "overlay.json")
+	activeOverlay = newOverlay()
+	defer func() {
+		activeRules, activeGoVer, *dryRun, *overlayOut, activeOverlay = origRules, origGoVer, origDryRun, origOverlayOut, origOverlay
+	}()
+
+	if err := processVendorTree(root, activeRules); err != nil {
+		t.Fatalf("processVendorTree: %v", err)
+	}
+
+	oldDir := filepath.Join(root, "vendor", "gopkg.in", "yaml.v3")
+	newDir := filepath.Join(root, "vendor", "go.yaml.in", "yaml", "v3")
+	if !fileExists(oldDir) {
+		t.Error("-overlay preview moved the vendor directory; it should still be at its original path")
+	}
+	if fileExists(newDir) {
+		t.Error("-overlay preview created the renamed vendor directory on disk")
+	}
+
+	onDisk, err := os.ReadFile(filepath.Join(root, "vendor", "modules.txt"))
+	if err != nil {
+		t.Fatalf("read modules.txt: %v", err)
+	}
+	if string(onDisk) != sampleModulesTxt {
+		t.Errorf("-overlay preview mutated modules.txt on disk:\ngot:  %q\nwant: %q", onDisk, sampleModulesTxt)
+	}
+}
+
+// TestProcessVendorTreeStagesModulesTxt guards against modules.txt being
+// written straight to disk with os.WriteFile instead of through the
+// overlay: a real run should leave modules.txt untouched until
+// activeOverlay.Commit() is called, even though the vendor directory itself
+// is renamed immediately (renameVendorDir's internal-import walk needs it
+// to exist on disk to run).
+func TestProcessVendorTreeStagesModulesTxt(t *testing.T) {
+	root := setupVendorTree(t)
+
+	origRules, origGoVer, origDryRun, origOverlayOut, origOverlay := activeRules, activeGoVer, *dryRun, *overlayOut, activeOverlay
+	activeRules = testRules(t)
+	activeGoVer = "1.22"
+	*dryRun = false
+	*overlayOut = ""
+	activeOverlay = newOverlay()
+	defer func() {
+		activeRules, activeGoVer, *dryRun, *overlayOut, activeOverlay = origRules, origGoVer, origDryRun, origOverlayOut, origOverlay
+	}()
+
+	if err := processVendorTree(root, activeRules); err != nil {
+		t.Fatalf("processVendorTree: %v", err)
+	}
+
+	modulesTxtPath := filepath.Join(root, "vendor", "modules.txt")
+	onDisk, err := os.ReadFile(modulesTxtPath)
+	if err != nil {
+		t.Fatalf("read modules.txt: %v", err)
+	}
+	if string(onDisk) != sampleModulesTxt {
+		t.Errorf("modules.txt was written directly to disk, bypassing the overlay:\ngot:  %q\nwant: %q", onDisk, sampleModulesTxt)
+	}
+
+	staged, ok := activeOverlay.entries[modulesTxtPath]
+	if !ok {
+		t.Fatal("processVendorTree did not stage modules.txt into the overlay")
+	}
+	if !strings.Contains(string(staged), "go.yaml.in/yaml/v3") {
+		t.Errorf("staged modules.txt missing rewritten module path:\n%s", staged)
+	}
+
+	if err := activeOverlay.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	onDisk, err = os.ReadFile(modulesTxtPath)
+	if err != nil {
+		t.Fatalf("read modules.txt after commit: %v", err)
+	}
+	if !strings.Contains(string(onDisk), "go.yaml.in/yaml/v3") {
+		t.Errorf("modules.txt on disk after Commit still missing rewritten path:\n%s", onDisk)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// collectFiles walks root and returns the .go files that would be processed,
+// in stable (lexical) order, applying the same vendor/.git, build-constraint
+// and generated-file skipping as visit. It does no parsing itself, so it's
+// cheap to run single-threaded before handing paths to the worker pool.
+func collectFiles(root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "vendor" || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		matched, err := matchesBuildConfig(path)
+		if err != nil {
+			return fmt.Errorf("check build constraints %s: %w", path, err)
+		}
+		if !matched {
+			fmt.Printf("[SKIP] %s (build constraints)\n", path)
+			return nil
+		}
+
+		if *skipGenerated {
+			generated, err := isGeneratedFile(path)
+			if err != nil {
+				return fmt.Errorf("check generated header %s: %w", path, err)
+			}
+			if generated {
+				fmt.Printf("[SKIP] %s (generated)\n", path)
+				return nil
+			}
+		}
+
+		paths = append(paths, path)
+		return nil
+	})
+	return paths, err
+}
+
+// changeLogLines returns the log line(s) for a processed file, matching the
+// original serial output ([DRY-RUN] would update vs [UPDATED]).
+func changeLogLines(path string, changed bool) []string {
+	if !changed {
+		return nil
+	}
+	if *dryRun {
+		return []string{fmt.Sprintf("[DRY-RUN] would update %s", path)}
+	}
+	return []string{fmt.Sprintf("[UPDATED] %s", path)}
+}
+
+// processFilesParallel processes paths (already selected and in stable
+// order) using a bounded worker pool sized by -j. Each file is parsed and
+// rewritten independently with its own token.FileSet. Per-file log records
+// are funneled through a single collector so SCAN/UPDATED output stays
+// deterministic and path-sorted no matter which worker finishes first; the
+// totalFiles/changedFiles counters are still updated atomically.
+func processFilesParallel(paths []string) error {
+	var mu sync.Mutex
+	records := make(map[string][]string, len(paths))
+
+	g := new(errgroup.Group)
+	g.SetLimit(*workers)
+
+	for _, path := range paths {
+		path := path // capture per-iteration; the tree pins no go directive to rely on 1.22+ semantics
+		g.Go(func() error {
+			atomic.AddUint64(&totalFiles, 1)
+			lines := []string{fmt.Sprintf("[SCAN] %s", path)}
+
+			changed, diffText, err := processGoFile(path)
+			if err != nil {
+				return fmt.Errorf("process %s: %w", path, err)
+			}
+			if changed {
+				atomic.AddUint64(&changedFiles, 1)
+				lines = append(lines, changeLogLines(path, changed)...)
+				if diffText != "" {
+					lines = append(lines, strings.TrimSuffix(diffText, "\n"))
+				}
+			}
+
+			mu.Lock()
+			records[path] = lines
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	runErr := g.Wait()
+
+	// Print in stable, path-sorted order regardless of completion order.
+	for _, path := range paths {
+		for _, line := range records[path] {
+			fmt.Println(line)
+		}
+	}
+
+	return runErr
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// BenchmarkProcessFilesParallel exercises the worker-pool scanning path
+// against a synthetic tree of generated .go files, so regressions in the
+// parallel walker are measurable on large monorepos.
+func BenchmarkProcessFilesParallel(b *testing.B) {
+	paths := generateBenchFiles(b, 500)
+
+	origRules, origGoVer, origDryRun := activeRules, activeGoVer, *dryRun
+	var err error
+	activeRules, err = defaultRules()
+	if err != nil {
+		b.Fatalf("default rules: %v", err)
+	}
+	activeGoVer = "1.22"
+	*dryRun = true
+	b.Cleanup(func() {
+		activeRules, activeGoVer = origRules, origGoVer
+		*dryRun = origDryRun
+	})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := processFilesParallel(paths); err != nil {
+			b.Fatalf("processFilesParallel: %v", err)
+		}
+	}
+}
+
+// generateBenchFiles writes n small .go files importing gopkg.in/yaml.v3
+// under testdata/bench, for use as a scanning benchmark fixture.
+func generateBenchFiles(b *testing.B, n int) []string {
+	b.Helper()
+
+	dir := filepath.Join("testdata", "bench")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		b.Fatalf("mkdir %s: %v", dir, err)
+	}
+	b.Cleanup(func() { os.RemoveAll(dir) })
+
+	paths := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("synthetic_%04d.go", i))
+		src := fmt.Sprintf("package bench\n\nimport \"gopkg.in/yaml.v3\"\n\nvar _%d = yaml.Marshal\n", i)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			b.Fatalf("write %s: %v", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
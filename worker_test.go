@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestProcessFilesParallelRewritesEveryFile guards against the g.Go closure
+// in processFilesParallel capturing the shared loop variable instead of a
+// per-iteration copy: with the bug, concurrent workers can race ahead of
+// the range loop and repeatedly process the same (often last) path,
+// leaving other files in the batch untouched.
+func TestProcessFilesParallelRewritesEveryFile(t *testing.T) {
+	dir := t.TempDir()
+	const n = 20
+	paths := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("f%02d.go", i))
+		src := fmt.Sprintf("package p\n\nimport \"gopkg.in/yaml.v3\"\n\nvar _%d = yaml.Marshal\n", i)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		paths = append(paths, path)
+	}
+
+	origRules, origGoVer, origDryRun, origWorkers, origOverlay := activeRules, activeGoVer, *dryRun, *workers, activeOverlay
+	activeRules = testRules(t)
+	activeGoVer = "1.22"
+	*dryRun = false
+	*workers = 4
+	activeOverlay = newOverlay()
+	defer func() {
+		activeRules, activeGoVer, *dryRun, *workers, activeOverlay = origRules, origGoVer, origDryRun, origWorkers, origOverlay
+	}()
+
+	if err := processFilesParallel(paths); err != nil {
+		t.Fatalf("processFilesParallel: %v", err)
+	}
+	if err := activeOverlay.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if !strings.Contains(string(data), "go.yaml.in/yaml/v3") {
+			t.Errorf("file %d (%s) was not rewritten:\n%s", i, path, data)
+		}
+		if !strings.Contains(string(data), fmt.Sprintf("var _%d", i)) {
+			t.Errorf("file %d (%s) lost its own content, got another file's:\n%s", i, path, data)
+		}
+	}
+}